@@ -0,0 +1,229 @@
+package log15
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// CborFormat returns a Format that encodes records as CBOR maps (RFC
+// 8949), one record per encoded item with no record separator - the
+// length prefix on the map itself is enough for a streaming decoder to
+// find the boundary. It implements FormatInto, so StreamHandler writes
+// it through a pooled buffer.
+//
+// Only the value types log15 itself ever puts in a Record - strings,
+// the builtin numeric kinds, bools, nil and anything with a String() or
+// Error() method - are given their natural CBOR encoding; anything else
+// falls back to its "%+v" text form, same as LogfmtFormat.
+func CborFormat() Format {
+	return cborFormat{}
+}
+
+type cborFormat struct{}
+
+func (f cborFormat) Format(r *Record) []byte {
+	buf := new(bytes.Buffer)
+	f.FormatInto(buf, r)
+	return buf.Bytes()
+}
+
+func (cborFormat) FormatInto(buf *bytes.Buffer, r *Record) {
+	cborWriteMapHeader(buf, uint64(3+len(r.Ctx)/2))
+
+	cborWriteString(buf, r.KeyNames.Time)
+	cborWriteString(buf, r.Time.Format(timeFormat))
+	cborWriteString(buf, r.KeyNames.Lvl)
+	cborWriteString(buf, r.Lvl.String())
+	cborWriteString(buf, r.KeyNames.Msg)
+	cborWriteString(buf, r.Msg)
+
+	for i := 0; i < len(r.Ctx); i += 2 {
+		cborWriteString(buf, ctxKeyString(r.Ctx[i]))
+		cborWriteValue(buf, r.Ctx[i+1])
+	}
+}
+
+func cborWriteMapHeader(buf *bytes.Buffer, n uint64) { cborWriteTypeLen(buf, 5, n) }
+
+func cborWriteTypeLen(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= 0xff:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(n))
+	case n <= 0xffff:
+		buf.WriteByte(major<<5 | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= 0xffffffff:
+		buf.WriteByte(major<<5 | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func cborWriteString(buf *bytes.Buffer, s string) {
+	cborWriteTypeLen(buf, 3, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func cborWriteInt(buf *bytes.Buffer, n int64) {
+	if n >= 0 {
+		cborWriteTypeLen(buf, 0, uint64(n))
+	} else {
+		cborWriteTypeLen(buf, 1, uint64(-(n + 1)))
+	}
+}
+
+func cborWriteFloat(buf *bytes.Buffer, f float64) {
+	buf.WriteByte(7<<5 | 27)
+	binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+}
+
+func cborWriteValue(buf *bytes.Buffer, v interface{}) {
+	v = formatShared(v)
+	switch x := v.(type) {
+	case nil:
+		buf.WriteByte(7<<5 | 22)
+	case bool:
+		if x {
+			buf.WriteByte(7<<5 | 21)
+		} else {
+			buf.WriteByte(7<<5 | 20)
+		}
+	case string:
+		cborWriteString(buf, x)
+	case int, int8, int16, int32, int64:
+		cborWriteInt(buf, reflect.ValueOf(x).Int())
+	case uint, uint8, uint16, uint32, uint64:
+		cborWriteTypeLen(buf, 0, reflect.ValueOf(x).Uint())
+	case float32:
+		cborWriteFloat(buf, float64(x))
+	case float64:
+		cborWriteFloat(buf, x)
+	default:
+		cborWriteString(buf, fmt.Sprintf("%+v", x))
+	}
+}
+
+func ctxKeyString(k interface{}) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%+v", k)
+}
+
+// MsgpackFormat returns a Format that encodes records as MessagePack
+// maps, using the same value mapping as CborFormat. It implements
+// FormatInto, so StreamHandler writes it through a pooled buffer.
+func MsgpackFormat() Format {
+	return msgpackFormat{}
+}
+
+type msgpackFormat struct{}
+
+func (f msgpackFormat) Format(r *Record) []byte {
+	buf := new(bytes.Buffer)
+	f.FormatInto(buf, r)
+	return buf.Bytes()
+}
+
+func (msgpackFormat) FormatInto(buf *bytes.Buffer, r *Record) {
+	msgpackWriteMapHeader(buf, uint64(3+len(r.Ctx)/2))
+
+	msgpackWriteString(buf, r.KeyNames.Time)
+	msgpackWriteString(buf, r.Time.Format(timeFormat))
+	msgpackWriteString(buf, r.KeyNames.Lvl)
+	msgpackWriteString(buf, r.Lvl.String())
+	msgpackWriteString(buf, r.KeyNames.Msg)
+	msgpackWriteString(buf, r.Msg)
+
+	for i := 0; i < len(r.Ctx); i += 2 {
+		msgpackWriteString(buf, ctxKeyString(r.Ctx[i]))
+		msgpackWriteValue(buf, r.Ctx[i+1])
+	}
+}
+
+func msgpackWriteMapHeader(buf *bytes.Buffer, n uint64) {
+	switch {
+	case n <= 15:
+		buf.WriteByte(0x80 | byte(n))
+	case n <= 0xffff:
+		buf.WriteByte(0xde)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(0xdf)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func msgpackWriteString(buf *bytes.Buffer, s string) {
+	switch n := len(s); {
+	case n <= 31:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= 0xff:
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(n))
+	case n <= 0xffff:
+		buf.WriteByte(0xda)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(0xdb)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+	buf.WriteString(s)
+}
+
+func msgpackWriteInt(buf *bytes.Buffer, n int64) {
+	if n >= 0 && n <= 0x7f {
+		buf.WriteByte(byte(n))
+		return
+	}
+	if n < 0 && n >= -32 {
+		buf.WriteByte(0xe0 | byte(n+32))
+		return
+	}
+	buf.WriteByte(0xd3)
+	binary.Write(buf, binary.BigEndian, n)
+}
+
+func msgpackWriteFloat(buf *bytes.Buffer, f float64) {
+	buf.WriteByte(0xcb)
+	binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+}
+
+func msgpackWriteValue(buf *bytes.Buffer, v interface{}) {
+	v = formatShared(v)
+	switch x := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if x {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case string:
+		msgpackWriteString(buf, x)
+	case int, int8, int16, int32, int64:
+		msgpackWriteInt(buf, reflect.ValueOf(x).Int())
+	case uint, uint8, uint16, uint32, uint64:
+		if u := reflect.ValueOf(x).Uint(); u > math.MaxInt64 {
+			buf.WriteByte(0xcf)
+			binary.Write(buf, binary.BigEndian, u)
+		} else {
+			msgpackWriteInt(buf, int64(u))
+		}
+	case float32:
+		msgpackWriteFloat(buf, float64(x))
+	case float64:
+		msgpackWriteFloat(buf, x)
+	default:
+		msgpackWriteString(buf, fmt.Sprintf("%+v", x))
+	}
+}
@@ -0,0 +1,35 @@
+package log15
+
+import (
+	"bytes"
+	"sync"
+)
+
+// bufPool recycles the *bytes.Buffer instances FormatInto-capable
+// formatters write into, so StreamHandler doesn't allocate a fresh
+// buffer (or a fresh []byte copy of one) for every record.
+var bufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	// don't let one huge record pin down an oversized buffer forever
+	if buf.Cap() > 64*1024 {
+		return
+	}
+	bufPool.Put(buf)
+}
+
+// FormatInto is an optional extension to Format for encoders that can
+// write directly into a caller-owned buffer. StreamHandler detects it
+// and routes through a pooled *bytes.Buffer instead of calling Format
+// and allocating a new []byte per record.
+type FormatInto interface {
+	FormatInto(buf *bytes.Buffer, r *Record)
+}
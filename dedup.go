@@ -0,0 +1,211 @@
+package log15
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Flusher is implemented by handlers that buffer records and need an
+// explicit signal to emit whatever they're holding onto, e.g. on
+// graceful shutdown. DedupHandler is one such handler.
+type Flusher interface {
+	Flush() error
+}
+
+// dedupLRUSize bounds how many distinct (Lvl, Msg, Ctx) groups
+// DedupHandler tracks concurrently. Once full, the least recently
+// touched group is flushed to make room for a new one.
+const dedupLRUSize = 128
+
+// DedupHandler returns a Handler that collapses records emitted in rapid
+// succession into a single record carrying a "count" context key, the
+// way syslog's "last message repeated N times" works. Records collapse
+// together when they hash equal over (Lvl, Msg, Ctx) and arrive within
+// window of the first record in the group; the pending record is
+// flushed to inner with its accumulated count once window elapses,
+// whether or not duplicates are still arriving - a continuously
+// repeating message is flushed every window, not held back forever.
+//
+// Lazy context values are evaluated once, the first time a group is
+// seen, so a collapsed group never re-runs the same lazy function.
+// DedupHandler implements Flusher so a pending record can be forced out
+// on shutdown instead of waiting for window to elapse.
+func DedupHandler(window time.Duration, inner Handler) Handler {
+	return &dedupHandler{
+		window:  window,
+		inner:   inner,
+		order:   list.New(),
+		entries: make(map[[32]byte]*list.Element),
+	}
+}
+
+type dedupEntry struct {
+	hash  [32]byte
+	rec   *Record
+	count int
+	timer *time.Timer
+}
+
+type dedupHandler struct {
+	window time.Duration
+	inner  Handler
+
+	mu      sync.Mutex
+	order   *list.List // front = most recently touched
+	entries map[[32]byte]*list.Element
+}
+
+// Log hashes the incoming record against (Lvl, Msg, Ctx) *before*
+// resolving any Lazy values in it, so a record that turns out to be a
+// duplicate never pays for evaluating its lazy context - only the first
+// record of a group does.
+func (d *dedupHandler) Log(r *Record) error {
+	hash := hashRecord(r)
+
+	d.mu.Lock()
+	if el, ok := d.entries[hash]; ok {
+		e := el.Value.(*dedupEntry)
+		e.count++
+		d.order.MoveToFront(el)
+		d.mu.Unlock()
+		return nil
+	}
+	d.mu.Unlock()
+
+	if err := resolveLazy(r); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// another record for the same group may have created the entry
+	// while we were resolving this one's lazy values above.
+	if el, ok := d.entries[hash]; ok {
+		e := el.Value.(*dedupEntry)
+		e.count++
+		d.order.MoveToFront(el)
+		return nil
+	}
+
+	e := &dedupEntry{hash: hash, rec: r, count: 1}
+	e.timer = time.AfterFunc(d.window, func() { d.expire(hash) })
+	d.entries[hash] = d.order.PushFront(e)
+
+	if d.order.Len() > dedupLRUSize {
+		d.evictOldestLocked()
+	}
+	return nil
+}
+
+// expire fires window after a group's first record arrived, whether or
+// not duplicates kept arriving in the meantime, and flushes whatever
+// count has accumulated by then.
+func (d *dedupHandler) expire(hash [32]byte) {
+	d.mu.Lock()
+	el, ok := d.entries[hash]
+	if !ok {
+		d.mu.Unlock()
+		return
+	}
+	e := el.Value.(*dedupEntry)
+	d.removeLocked(el)
+	d.mu.Unlock()
+
+	d.emit(e)
+}
+
+// evictOldestLocked drops the least recently touched group to keep the
+// LRU within dedupLRUSize, flushing it so its records aren't lost.
+func (d *dedupHandler) evictOldestLocked() {
+	el := d.order.Back()
+	if el == nil {
+		return
+	}
+	d.removeLocked(el)
+	e := el.Value.(*dedupEntry)
+	e.timer.Stop()
+	go d.emit(e)
+}
+
+func (d *dedupHandler) removeLocked(el *list.Element) {
+	e := el.Value.(*dedupEntry)
+	delete(d.entries, e.hash)
+	d.order.Remove(el)
+}
+
+func (d *dedupHandler) emit(e *dedupEntry) error {
+	if e.count > 1 {
+		e.rec.Ctx = append(e.rec.Ctx, "count", e.count)
+	}
+	return d.inner.Log(e.rec)
+}
+
+// Flush implements Flusher, immediately emitting every pending group.
+func (d *dedupHandler) Flush() error {
+	d.mu.Lock()
+	entries := make([]*dedupEntry, 0, d.order.Len())
+	for el := d.order.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*dedupEntry)
+		e.timer.Stop()
+		entries = append(entries, e)
+	}
+	d.order.Init()
+	d.entries = make(map[[32]byte]*list.Element)
+	d.mu.Unlock()
+
+	var firstErr error
+	for _, e := range entries {
+		if err := d.emit(e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// resolveLazy evaluates any Lazy context values in place, so
+// DedupHandler only ever runs a lazy function once per collapsed group
+// rather than once per incoming record. Unlike LazyHandler, it returns
+// the first evaluation error instead of recording it in the context, and
+// DedupHandler then drops the record.
+func resolveLazy(r *Record) error {
+	for i := 1; i < len(r.Ctx); i += 2 {
+		lz, ok := r.Ctx[i].(Lazy)
+		if !ok {
+			continue
+		}
+		v, err := evaluateLazy(lz)
+		if err != nil {
+			return err
+		}
+		r.Ctx[i] = v
+	}
+	return nil
+}
+
+// hashRecord content-hashes (Lvl, Msg, Ctx) so DedupHandler can tell
+// whether two records are "the same" log line. It runs before Lazy
+// values are resolved, so a Lazy is hashed by its function pointer
+// rather than by calling it - calling it here would defeat evaluating it
+// only once per collapsed group.
+func hashRecord(r *Record) [32]byte {
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, int64(r.Lvl))
+	fmt.Fprint(h, r.Msg)
+	for _, v := range r.Ctx {
+		if lz, ok := v.(Lazy); ok {
+			fmt.Fprintf(h, "\x00lazy:%#x", reflect.ValueOf(lz.Fn).Pointer())
+			continue
+		}
+		fmt.Fprintf(h, "\x00%v", v)
+	}
+
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
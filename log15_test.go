@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net"
 	"testing"
 	"time"
@@ -185,6 +186,43 @@ func TestLogContext(t *testing.T) {
 	}
 }
 
+func TestLogContextInheritsHandler(t *testing.T) {
+	t.Parallel()
+
+	l := New()
+	h1 := &testHandler{}
+	l.SetHandler(h1)
+
+	child := l.New("foo", "bar")
+	if child.GetHandler() != l.GetHandler() {
+		t.Fatalf("expected child to inherit parent's handler")
+	}
+
+	// a handler change on the parent after New() is still visible to
+	// the child, since it never set its own handler.
+	h2 := &testHandler{}
+	l.SetHandler(h2)
+	child.Crit("baz")
+	if h2.r.Msg != "baz" {
+		t.Fatalf("expected child to observe parent's later SetHandler, got msg %q on h2", h2.r.Msg)
+	}
+	if h1.r.Msg != "" {
+		t.Fatalf("expected old handler to receive nothing after parent switched, got msg %q", h1.r.Msg)
+	}
+
+	// once the child sets its own handler, it stops tracking the parent.
+	h3 := &testHandler{}
+	child.SetHandler(h3)
+	l.SetHandler(&testHandler{})
+	child.Info("qux")
+	if h3.r.Msg != "qux" {
+		t.Fatalf("expected child's own handler to receive record, got msg %q", h3.r.Msg)
+	}
+	if h2.r.Msg != "baz" {
+		t.Fatalf("expected child to stop forwarding to parent's handler after SetHandler, got msg %q", h2.r.Msg)
+	}
+}
+
 func TestLvlFilterHandler(t *testing.T) {
 	t.Parallel()
 
@@ -274,6 +312,114 @@ func TestMatchFilterHandler(t *testing.T) {
 	}
 }
 
+func TestSubsysFilterHandler(t *testing.T) {
+	t.Setenv("LOG15_TEST_STTRACE", "net,idx")
+
+	h := &testHandler{}
+	f := SubsysFilterHandler("LOG15_TEST_STTRACE", h)
+
+	l := New()
+	l.SetHandler(f)
+
+	l.Subsystem("scanner").Debug("scanning")
+	if h.r.Msg != "" {
+		t.Fatalf("expected scanner subsystem to be filtered out, got msg %q", h.r.Msg)
+	}
+
+	l.Subsystem("net").Debug("dialing")
+	if h.r.Msg != "dialing" {
+		t.Fatalf("expected net subsystem to pass, got msg %q", h.r.Msg)
+	}
+
+	l.Info("untagged")
+	if h.r.Msg != "untagged" {
+		t.Fatalf("expected untagged records to always pass, got msg %q", h.r.Msg)
+	}
+
+	l.Subsystem("scanner").Crit("scanner on fire")
+	if h.r.Msg != "scanner on fire" {
+		t.Fatalf("expected Crit to bypass the subsystem filter, got msg %q", h.r.Msg)
+	}
+}
+
+func TestDedupHandler(t *testing.T) {
+	t.Parallel()
+
+	ch := make(chan Record)
+	l := New()
+	l.SetHandler(DedupHandler(50*time.Millisecond, &waitHandler{ch}))
+
+	l.Info("repeated")
+	l.Info("repeated")
+	l.Info("repeated")
+
+	select {
+	case r := <-ch:
+		if r.Msg != "repeated" {
+			t.Fatalf("expected pending record to flush after window, got msg %q", r.Msg)
+		}
+		count, ok := r.Ctx[1].(int)
+		if !ok || count != 3 {
+			t.Fatalf("expected count=3 in flushed record, got %v", r.Ctx)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected dedup window to flush the pending record")
+	}
+}
+
+func TestDedupHandlerFlushesSteadyStream(t *testing.T) {
+	t.Parallel()
+
+	ch := make(chan Record)
+	l := New()
+	window := 50 * time.Millisecond
+	l.SetHandler(DedupHandler(window, &waitHandler{ch}))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				l.Info("repeated")
+				time.Sleep(window / 10)
+			}
+		}
+	}()
+
+	select {
+	case r := <-ch:
+		if r.Msg != "repeated" {
+			t.Fatalf("expected flushed record to be %q, got %q", "repeated", r.Msg)
+		}
+	case <-time.After(3 * window):
+		t.Fatalf("expected the group to flush after window even with duplicates still arriving")
+	}
+}
+
+func TestDedupHandlerFlush(t *testing.T) {
+	t.Parallel()
+
+	h := &testHandler{}
+	l := New()
+	d := DedupHandler(time.Minute, h)
+	l.SetHandler(d)
+
+	l.Info("pending")
+	if h.r.Msg != "" {
+		t.Fatalf("expected record to be held pending before Flush")
+	}
+
+	if err := d.(Flusher).Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	if h.r.Msg != "pending" {
+		t.Fatalf("expected Flush to emit the pending record, got msg %q", h.r.Msg)
+	}
+}
+
 type failingWriter struct {
 	fail bool
 }
@@ -318,3 +464,60 @@ func TestFailoverHandler(t *testing.T) {
 		t.Fatalf("expected failover ctx. got: %s, expected %s", got, expected)
 	}
 }
+
+func TestCborFormat(t *testing.T) {
+	t.Parallel()
+
+	l, buf := testFormatter(CborFormat())
+	l.Error("some message", "x", 1)
+
+	if buf.Len() == 0 {
+		t.Fatalf("expected CborFormat to write some bytes")
+	}
+	// a CBOR map header for 4 pairs (t, lvl, msg, x) is 0xa4.
+	if buf.Bytes()[0] != 0xa4 {
+		t.Fatalf("expected a 4-entry map header, got %#x", buf.Bytes()[0])
+	}
+}
+
+func TestMsgpackFormat(t *testing.T) {
+	t.Parallel()
+
+	l, buf := testFormatter(MsgpackFormat())
+	l.Error("some message", "x", 1)
+
+	if buf.Len() == 0 {
+		t.Fatalf("expected MsgpackFormat to write some bytes")
+	}
+	// a fixmap header for 4 pairs (t, lvl, msg, x) is 0x84.
+	if buf.Bytes()[0] != 0x84 {
+		t.Fatalf("expected a 4-entry fixmap header, got %#x", buf.Bytes()[0])
+	}
+}
+
+func benchmarkStreamHandler(b *testing.B, fmtr Format) {
+	l := New()
+	l.SetHandler(StreamHandler(ioutil.Discard, fmtr))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("benchmark message", "i", i, "x", 3.2)
+	}
+}
+
+func BenchmarkStreamHandlerJson(b *testing.B) {
+	benchmarkStreamHandler(b, JsonFormat())
+}
+
+func BenchmarkStreamHandlerLogfmt(b *testing.B) {
+	benchmarkStreamHandler(b, LogfmtFormat())
+}
+
+func BenchmarkStreamHandlerCbor(b *testing.B) {
+	benchmarkStreamHandler(b, CborFormat())
+}
+
+func BenchmarkStreamHandlerMsgpack(b *testing.B) {
+	benchmarkStreamHandler(b, MsgpackFormat())
+}
@@ -0,0 +1,239 @@
+package log15
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-stack/stack"
+)
+
+const timeKey = "t"
+const lvlKey = "lvl"
+const msgKey = "msg"
+const errorKey = "LOG15_ERROR"
+
+// Lvl is a log level, ordered from the most to the least severe.
+type Lvl int
+
+const (
+	LvlCrit Lvl = iota
+	LvlError
+	LvlWarn
+	LvlInfo
+	LvlDebug
+)
+
+// String returns the name of a Lvl.
+func (l Lvl) String() string {
+	switch l {
+	case LvlDebug:
+		return "dbug"
+	case LvlInfo:
+		return "info"
+	case LvlWarn:
+		return "warn"
+	case LvlError:
+		return "eror"
+	case LvlCrit:
+		return "crit"
+	default:
+		panic("bad level")
+	}
+}
+
+// LvlFromString returns the appropriate Lvl from a string name. Useful
+// for parsing command line args and configuration files.
+func LvlFromString(lvlString string) (Lvl, error) {
+	switch lvlString {
+	case "debug", "dbug":
+		return LvlDebug, nil
+	case "info":
+		return LvlInfo, nil
+	case "warn":
+		return LvlWarn, nil
+	case "error", "eror":
+		return LvlError, nil
+	case "crit":
+		return LvlCrit, nil
+	default:
+		return LvlDebug, fmt.Errorf("unknown level: %v", lvlString)
+	}
+}
+
+// A Record is what a Logger asks its Handler to write.
+type Record struct {
+	Time     time.Time
+	Lvl      Lvl
+	Msg      string
+	Ctx      []interface{}
+	Call     stack.Call
+	KeyNames RecordKeyNames
+}
+
+// RecordKeyNames gets stored in a Record when the write function is executed.
+type RecordKeyNames struct {
+	Time string
+	Msg  string
+	Lvl  string
+}
+
+// A Logger writes key/value pairs to a Handler.
+type Logger interface {
+	// New returns a new Logger that has this logger's context plus the
+	// given context.
+	New(ctx ...interface{}) Logger
+
+	// GetHandler returns the Handler currently in effect for this
+	// Logger: either its own, if SetHandler was called on it directly,
+	// or the one it inherits from its parent.
+	GetHandler() Handler
+
+	// SetHandler updates the logger to write records to the specified
+	// handler. Loggers created from this one via New before the call
+	// keep tracking it live unless they have their own handler set;
+	// loggers created after the call start from this handler.
+	SetHandler(h Handler)
+
+	// Log a message at the given level with context key/value pairs
+	Debug(msg string, ctx ...interface{})
+	Info(msg string, ctx ...interface{})
+	Warn(msg string, ctx ...interface{})
+	Error(msg string, ctx ...interface{})
+	Crit(msg string, ctx ...interface{})
+
+	// Subsystem returns a child Logger whose records are tagged with
+	// name under the "subsys" context key, for use with
+	// SubsysFilterHandler.
+	Subsystem(name string) Logger
+}
+
+type logger struct {
+	ctx []interface{}
+	h   *swapHandler
+}
+
+func (l *logger) write(msg string, lvl Lvl, ctx []interface{}) {
+	r := Record{
+		Time: time.Now(),
+		Lvl:  lvl,
+		Msg:  msg,
+		Ctx:  newContext(l.ctx, ctx),
+		KeyNames: RecordKeyNames{
+			Time: timeKey,
+			Msg:  msgKey,
+			Lvl:  lvlKey,
+		},
+	}
+	r.Call = stack.Caller(2)
+	l.h.Log(&r)
+}
+
+// New returns a child Logger whose context is this logger's context plus
+// ctx. The child starts out tracking this logger's handler live — a
+// later SetHandler on the parent is visible to the child — but the link
+// is copy-on-write: the moment the child gets its own SetHandler call,
+// it stops forwarding to the parent and keeps whatever was set.
+func (l *logger) New(ctx ...interface{}) Logger {
+	child := &logger{newContext(l.ctx, ctx), new(swapHandler)}
+	child.SetHandler(l.h)
+	return child
+}
+
+func newContext(prefix []interface{}, suffix []interface{}) []interface{} {
+	normalizedSuffix := normalize(suffix)
+	newCtx := append(prefix[:len(prefix):len(prefix)], normalizedSuffix...)
+	return newCtx
+}
+
+func (l *logger) Debug(msg string, ctx ...interface{}) {
+	l.write(msg, LvlDebug, ctx)
+}
+
+func (l *logger) Info(msg string, ctx ...interface{}) {
+	l.write(msg, LvlInfo, ctx)
+}
+
+func (l *logger) Warn(msg string, ctx ...interface{}) {
+	l.write(msg, LvlWarn, ctx)
+}
+
+func (l *logger) Error(msg string, ctx ...interface{}) {
+	l.write(msg, LvlError, ctx)
+}
+
+func (l *logger) Crit(msg string, ctx ...interface{}) {
+	l.write(msg, LvlCrit, ctx)
+}
+
+// GetHandler returns the Handler currently in effect for this logger,
+// unwrapping the swapHandler chain New() builds so a child's
+// inherited-but-never-overridden handler introspects as the same value
+// its parent would report.
+func (l *logger) GetHandler() Handler {
+	h := l.h.Get()
+	for {
+		sh, ok := h.(*swapHandler)
+		if !ok {
+			return h
+		}
+		h = sh.Get()
+	}
+}
+
+func (l *logger) SetHandler(h Handler) {
+	l.h.Swap(h)
+}
+
+func (l *logger) Subsystem(name string) Logger {
+	return l.New(subsysKey, name)
+}
+
+// New returns a new logger with the given context and no handler set
+// (records are discarded until SetHandler is called).
+func New(ctx ...interface{}) Logger {
+	root := &logger{normalize(ctx), new(swapHandler)}
+	root.SetHandler(DiscardHandler())
+	return root
+}
+
+func normalize(ctx []interface{}) []interface{} {
+	// if the caller passed a Ctx object, then expand it
+	if len(ctx) == 1 {
+		if ctxMap, ok := ctx[0].(Ctx); ok {
+			ctx = ctxMap.toArray()
+		}
+	}
+
+	// ctx needs to be even because it's a series of key/value pairs
+	// no one wants to check for out of range errors here
+	if len(ctx)%2 != 0 {
+		ctx = append(ctx, nil, errorKey, "Normalized odd number of arguments by adding nil")
+	}
+
+	return ctx
+}
+
+// Lazy allows you to defer calculation of a logged value that is
+// expensive to compute until it is certain that it must be evaluated
+// with the given filters.
+type Lazy struct {
+	Fn interface{}
+}
+
+// Ctx is a map of key/value pairs to pass as context to a log function.
+// Use this only if you need greater safety around the arguments you pass
+// to the logging functions.
+type Ctx map[string]interface{}
+
+func (c Ctx) toArray() []interface{} {
+	arr := make([]interface{}, len(c)*2)
+
+	i := 0
+	for k, v := range c {
+		arr[i] = k
+		arr[i+1] = v
+		i += 2
+	}
+
+	return arr
+}
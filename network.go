@@ -0,0 +1,205 @@
+package log15
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// DropPolicy controls what a background writer does once its queue
+// fills up: either block the caller until there is room, or drop the
+// record and keep going.
+type DropPolicy int
+
+const (
+	// PolicyBlock blocks Log calls until the background writer has
+	// room to accept another record.
+	PolicyBlock DropPolicy = iota
+	// PolicyDrop discards records instead of blocking the caller.
+	PolicyDrop
+)
+
+const (
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 30 * time.Second
+
+	// netQueueSize is the default bound on NetHandler's background
+	// queue.
+	netQueueSize = 1024
+)
+
+// jitter returns d plus up to 25% random variation, so that many
+// reconnecting clients don't hammer the remote end in lockstep.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Int63n(int64(d)/4+1))
+}
+
+// backgroundQueue runs jobs on its own goroutine, retrying a failed job
+// with an exponential backoff (capped, with jitter) until it succeeds or
+// the queue is closed. It is the primitive NetHandler and the syslog
+// handlers use to ship records without blocking the caller on the
+// network.
+type backgroundQueue struct {
+	jobs   chan func() error
+	policy DropPolicy
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newBackgroundQueue(size int, policy DropPolicy) *backgroundQueue {
+	q := &backgroundQueue{
+		jobs:   make(chan func() error, size),
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// submit enqueues job, honoring the queue's overflow policy.
+func (q *backgroundQueue) submit(job func() error) {
+	if q.policy == PolicyDrop {
+		select {
+		case q.jobs <- job:
+		default:
+		}
+		return
+	}
+
+	select {
+	case q.jobs <- job:
+	case <-q.done:
+	}
+}
+
+func (q *backgroundQueue) run() {
+	backoff := minBackoff
+	for {
+		select {
+		case job, ok := <-q.jobs:
+			if !ok {
+				return
+			}
+			for {
+				if err := job(); err == nil {
+					backoff = minBackoff
+					break
+				}
+				select {
+				case <-time.After(jitter(backoff)):
+				case <-q.done:
+					return
+				}
+				if backoff *= 2; backoff > maxBackoff {
+					backoff = maxBackoff
+				}
+			}
+		case <-q.done:
+			return
+		}
+	}
+}
+
+func (q *backgroundQueue) Close() error {
+	q.closeOnce.Do(func() { close(q.done) })
+	return nil
+}
+
+// netWriter is an io.WriteCloser that ships writes to a network
+// connection from a backgroundQueue, dialing lazily and redialing with
+// backoff whenever the connection drops.
+type netWriter struct {
+	dial func() (net.Conn, error)
+	q    *backgroundQueue
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newNetWriter(network, addr string, queueSize int, policy DropPolicy) *netWriter {
+	w := &netWriter{
+		dial: func() (net.Conn, error) { return net.Dial(network, addr) },
+		q:    newBackgroundQueue(queueSize, policy),
+	}
+	return w
+}
+
+func (w *netWriter) Write(p []byte) (int, error) {
+	buf := append([]byte(nil), p...)
+	w.q.submit(func() error {
+		conn, err := w.connect()
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Write(buf); err != nil {
+			conn.Close()
+			w.mu.Lock()
+			if w.conn == conn {
+				w.conn = nil
+			}
+			w.mu.Unlock()
+			return err
+		}
+		return nil
+	})
+	return len(p), nil
+}
+
+func (w *netWriter) connect() (net.Conn, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		return w.conn, nil
+	}
+	conn, err := w.dial()
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return conn, nil
+}
+
+func (w *netWriter) Close() error {
+	w.mu.Lock()
+	conn := w.conn
+	w.conn = nil
+	w.mu.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+	return w.q.Close()
+}
+
+var _ io.WriteCloser = (*netWriter)(nil)
+
+// NetHandler opens a socket to the given address and writes all log
+// records to it in logfmt/JSON/whatever fmtr, reconnecting with
+// exponential backoff if the connection is lost. Records are queued on a
+// bounded channel (see NetHandlerWithPolicy to size it or change the
+// overflow policy) so a slow or unreachable remote never blocks the
+// caller.
+func NetHandler(network, addr string, fmtr Format) (Handler, error) {
+	return NetHandlerWithPolicy(network, addr, fmtr, netQueueSize, PolicyDrop)
+}
+
+// NetHandlerWithPolicy is like NetHandler but lets the caller size the
+// background queue and choose what happens when it is full.
+func NetHandlerWithPolicy(network, addr string, fmtr Format, queueSize int, policy DropPolicy) (Handler, error) {
+	w := newNetWriter(network, addr, queueSize, policy)
+	return &closingHandler{w, StreamHandler(w, fmtr)}, nil
+}
+
+// NetHandler opens a socket to the given address and writes all log
+// records to it, or panics on error.
+func (m muster) NetHandler(network, addr string, fmtr Format) Handler {
+	h, err := NetHandler(network, addr, fmtr)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
@@ -0,0 +1,81 @@
+package log15
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNetHandlerReconnects(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string, 8)
+	conns := make(chan net.Conn, 8)
+	accept := func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conns <- c
+		go func() {
+			s := bufio.NewScanner(c)
+			for s.Scan() {
+				lines <- s.Text()
+			}
+			c.Close()
+		}()
+	}
+	go accept()
+
+	h, err := NetHandler("tcp", ln.Addr().String(), LogfmtFormat())
+	if err != nil {
+		t.Fatalf("NetHandler: %v", err)
+	}
+	defer h.(*closingHandler).Close()
+
+	l := New()
+	l.SetHandler(h)
+
+	l.Info("first")
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, "msg=first") {
+			t.Fatalf("expected first record to contain %q, got %q", "msg=first", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for first record")
+	}
+
+	// Break the connection from the server side, then accept a new one:
+	// the writer should notice the failed write, redial, and keep
+	// delivering records without the caller ever seeing an error.
+	select {
+	case c := <-conns:
+		c.Close()
+	case <-time.After(time.Second):
+		t.Fatalf("never observed the accepted connection")
+	}
+	go accept()
+
+	deadline := time.After(2 * time.Second)
+	for {
+		l.Info("retry")
+		select {
+		case line := <-lines:
+			if strings.Contains(line, "msg=retry") {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("handler never reconnected and delivered a record")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package log15
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RotateOptions configures the rotation, retention and compression
+// behavior of a RotatingFileHandler.
+type RotateOptions struct {
+	// MaxSize is the size in bytes a log file is allowed to reach before
+	// it is rotated. Zero disables size-based rotation.
+	MaxSize int64
+
+	// Interval is the time period after which the file is rotated, e.g.
+	// time.Hour or 24*time.Hour. Any duration is accepted, including
+	// minute-based intervals. Zero disables time-based rotation.
+	Interval time.Duration
+
+	// MaxBackups is the maximum number of rotated files to retain. Files
+	// beyond this count, oldest first, are deleted. Zero means
+	// unlimited.
+	MaxBackups int
+
+	// MaxAge is the maximum age a rotated file may reach before it is
+	// deleted, regardless of MaxBackups. Zero means files are never
+	// removed because of their age.
+	MaxAge time.Duration
+
+	// Compress causes rotated files to be gzip-compressed in a
+	// background goroutine after rotation.
+	Compress bool
+}
+
+// rotatingFile is an io.WriteCloser that rotates the underlying file
+// according to a RotateOptions. It is safe for concurrent use.
+type rotatingFile struct {
+	filename string
+	opts     RotateOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	rotateAt time.Time
+}
+
+// RotatingFileHandler returns a handler that writes log records to the
+// given path using fmtr, rotating the file according to opts. Rotation
+// can be triggered by size, by a fixed time interval, or both; whichever
+// condition is hit first rotates the file.
+func RotatingFileHandler(path string, fmtr Format, opts RotateOptions) (Handler, error) {
+	f, err := newRotatingFile(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &closingHandler{f, StreamHandler(f, fmtr)}, nil
+}
+
+func newRotatingFile(filename string, opts RotateOptions) (*rotatingFile, error) {
+	rf := &rotatingFile{
+		filename: filename,
+		opts:     opts,
+	}
+	rf.calcNextRotateTime()
+	if err := rf.openFile(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// Write implements io.Writer, rotating the file first if necessary.
+func (rf *rotatingFile) Write(data []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if err := rf.openFile(); err != nil {
+		return 0, err
+	}
+
+	if rf.shouldRotate(len(data)) {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(data)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close implements io.Closer.
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.closeFile()
+}
+
+func (rf *rotatingFile) closeFile() error {
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+func (rf *rotatingFile) openFile() error {
+	if rf.file != nil {
+		return nil
+	}
+
+	fd, err := os.OpenFile(rf.filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	fi, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return err
+	}
+
+	rf.file = fd
+	rf.size = fi.Size()
+	return nil
+}
+
+func (rf *rotatingFile) shouldRotate(nextWrite int) bool {
+	if rf.opts.MaxSize > 0 && rf.size+int64(nextWrite) > rf.opts.MaxSize {
+		return true
+	}
+	if rf.opts.Interval > 0 && !time.Now().Before(rf.rotateAt) {
+		return true
+	}
+	return false
+}
+
+func (rf *rotatingFile) calcNextRotateTime() {
+	if rf.opts.Interval > 0 {
+		rf.rotateAt = time.Now().Add(rf.opts.Interval)
+	}
+}
+
+// rotate closes the current file, renames it with a timestamped or
+// numeric suffix, kicks off compression if requested, enforces
+// retention, and reopens the base filename for further writes.
+func (rf *rotatingFile) rotate() error {
+	if err := rf.closeFile(); err != nil {
+		return err
+	}
+
+	dst := rf.backupName()
+	if err := os.Rename(rf.filename, dst); err != nil {
+		return err
+	}
+
+	if rf.opts.Compress || rf.opts.MaxBackups > 0 || rf.opts.MaxAge > 0 {
+		// Run compression and retention in the same background
+		// goroutine, in that order, so retention's directory glob
+		// never observes dst mid-compression (as both the
+		// uncompressed file and its eventual .gz) and never races
+		// compressFile's read of dst against its removal.
+		go func() {
+			if rf.opts.Compress {
+				compressFile(dst)
+			}
+			if rf.opts.MaxBackups > 0 || rf.opts.MaxAge > 0 {
+				deleteExpiredFiles(rf.filename, rf.opts.MaxBackups, rf.opts.MaxAge)
+			}
+		}()
+	}
+
+	rf.calcNextRotateTime()
+	rf.size = 0
+	return rf.openFile()
+}
+
+// backupName derives the rotated file's name. Files rotated on a time
+// interval get a second-precision timestamp suffix; files rotated
+// purely on size get an incrementing numeric suffix. Either way, if the
+// resulting name is already taken - two rotations landing in the same
+// second, or a re-used numeric suffix - a numeric suffix is appended
+// until a free name is found, so rotate never clobbers an earlier
+// backup. A candidate counts as taken if its gzipped form exists too:
+// compressFile removes the uncompressed file once it produces the
+// ".gz", so checking only the uncompressed name would let a later
+// rotation reuse the same suffix and gzip over that earlier backup.
+func (rf *rotatingFile) backupName() string {
+	if rf.opts.Interval > 0 {
+		base := rf.filename + "." + time.Now().Format("20060102150405")
+		if !rf.backupNameTaken(base) {
+			return base
+		}
+		for i := 1; ; i++ {
+			candidate := base + "." + strconv.Itoa(i)
+			if !rf.backupNameTaken(candidate) {
+				return candidate
+			}
+		}
+	}
+
+	for i := 1; ; i++ {
+		candidate := rf.filename + "." + strconv.Itoa(i)
+		if !rf.backupNameTaken(candidate) {
+			return candidate
+		}
+	}
+}
+
+func (rf *rotatingFile) backupNameTaken(candidate string) bool {
+	if _, err := os.Stat(candidate); err == nil {
+		return true
+	}
+	_, err := os.Stat(candidate + ".gz")
+	return err == nil
+}
+
+// compressFile gzips path in place, removing the uncompressed file on
+// success. Failures are ignored; the uncompressed backup is left in
+// place so no data is lost.
+func compressFile(path string) {
+	src, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+
+	gw := gzip.NewWriter(dst)
+	if _, err := io.Copy(gw, src); err != nil {
+		gw.Close()
+		dst.Close()
+		os.Remove(path + ".gz")
+		return
+	}
+	if err := gw.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return
+	}
+
+	os.Remove(path)
+}
+
+// deleteExpiredFiles enforces MaxBackups and MaxAge by globbing for
+// rotated copies of base, sorting them by mtime, and removing whatever
+// falls outside the retention window.
+func deleteExpiredFiles(base string, maxBackups int, maxAge time.Duration) {
+	matches, err := filepath.Glob(fmt.Sprintf("%s.*", base))
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+
+	backups := make([]backup, 0, len(matches))
+	for _, m := range matches {
+		fi, err := os.Stat(m)
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		backups = append(backups, backup{m, fi.ModTime()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+
+	now := time.Now()
+	for i, b := range backups {
+		expiredByAge := maxAge > 0 && now.Sub(b.modTime) > maxAge
+		expiredByCount := maxBackups > 0 && i >= maxBackups
+		if expiredByAge || expiredByCount {
+			os.Remove(b.path)
+		}
+	}
+}
+
+// RotatingFileHandler returns a RotatingFileHandler, or panics on error.
+func (m muster) RotatingFileHandler(path string, fmtr Format, opts RotateOptions) Handler {
+	h, err := RotatingFileHandler(path, fmtr, opts)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
@@ -0,0 +1,136 @@
+package log15
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileHandlerSizeTrigger(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "log15-rotate")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.log")
+	h, err := RotatingFileHandler(path, LogfmtFormat(), RotateOptions{MaxSize: 40})
+	if err != nil {
+		t.Fatalf("RotatingFileHandler: %v", err)
+	}
+	defer h.(*closingHandler).Close()
+
+	l := New()
+	l.SetHandler(h)
+
+	for i := 0; i < 10; i++ {
+		l.Info("a reasonably long line to force rotation by size")
+	}
+
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(matches) == 0 {
+		t.Fatalf("expected at least one rotated backup in %s, found none", dir)
+	}
+}
+
+func TestRotatingFileHandlerCompressDoesNotReuseSuffix(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "log15-compress")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.log")
+	h, err := RotatingFileHandler(path, LogfmtFormat(), RotateOptions{MaxSize: 1, Compress: true})
+	if err != nil {
+		t.Fatalf("RotatingFileHandler: %v", err)
+	}
+	defer h.(*closingHandler).Close()
+
+	l := New()
+	l.SetHandler(h)
+
+	const rotations = 4
+	for i := 0; i < rotations; i++ {
+		l.Info("line forcing its own rotation")
+
+		// compressFile removes the uncompressed backup once it writes
+		// the .gz, leaving only the .gz to hold that numeric suffix;
+		// wait for that to happen before triggering the next rotation,
+		// so the next rotation gets a distinct suffix only if
+		// backupName counts the .gz as taken.
+		deadline := time.Now().Add(time.Second)
+		for {
+			matches, err := filepath.Glob(path + ".*.gz")
+			if err != nil {
+				t.Fatalf("Glob: %v", err)
+			}
+			if len(matches) == i+1 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for rotation %d to compress, found %v", i, matches)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	matches, err := filepath.Glob(path + ".*.gz")
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(matches) != rotations {
+		t.Fatalf("expected %d distinct compressed backups, got %d: %v", rotations, len(matches), matches)
+	}
+}
+
+func TestRotatingFileHandlerRetention(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "log15-retention")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.log")
+	h, err := RotatingFileHandler(path, LogfmtFormat(), RotateOptions{MaxSize: 1, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("RotatingFileHandler: %v", err)
+	}
+	defer h.(*closingHandler).Close()
+
+	l := New()
+	l.SetHandler(h)
+
+	for i := 0; i < 6; i++ {
+		l.Info("line forcing its own rotation")
+	}
+
+	// retention runs in a background goroutine; give it a moment to settle.
+	var matches []string
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		matches, err = filepath.Glob(path + ".*")
+		if err != nil {
+			t.Fatalf("Glob: %v", err)
+		}
+		if len(matches) <= 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(matches) > 2 {
+		t.Fatalf("expected MaxBackups=2 to be enforced, found %d backups: %v", len(matches), matches)
+	}
+}
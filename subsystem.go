@@ -0,0 +1,102 @@
+package log15
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// subsysKey is the Ctx key Logger.Subsystem tags records with.
+const subsysKey = "subsys"
+
+// SubsysFilter is the Handler returned by SubsysFilterHandler. Besides
+// implementing Handler, it exposes Reload so a caller holding onto the
+// concrete type can force a re-read of the environment variable without
+// restarting the process or sending SIGHUP.
+type SubsysFilter struct {
+	envVar string
+	inner  Handler
+
+	mu      sync.RWMutex
+	all     bool
+	enabled map[string]bool
+}
+
+// SubsysFilterHandler returns a Handler that gates LvlDebug records
+// tagged with a subsystem (see Logger.Subsystem), only forwarding them
+// to h when their subsystem appears in the comma- or space-separated
+// list of names held in the envVar environment variable, or that list
+// contains "all". Records at LvlInfo and above are always forwarded
+// regardless of envVar, as are untagged records, so this filter can
+// never suppress a warning, error, or critical log. This is the
+// STTRACE=net,idx pattern syncthing uses for per-package debug toggling
+// without recompiling.
+//
+// envVar is parsed once at construction. Send the process SIGHUP, or
+// call Reload on the returned filter, to pick up a change to envVar at
+// runtime.
+func SubsysFilterHandler(envVar string, h Handler) Handler {
+	f := &SubsysFilter{envVar: envVar, inner: h}
+	f.Reload()
+	f.watchSIGHUP()
+	return f
+}
+
+// Log implements Handler.
+func (f *SubsysFilter) Log(r *Record) error {
+	if r.Lvl == LvlDebug {
+		if name, ok := subsysOf(r); ok && !f.enabledFor(name) {
+			return nil
+		}
+	}
+	return f.inner.Log(r)
+}
+
+func (f *SubsysFilter) enabledFor(name string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.all || f.enabled[name]
+}
+
+// Reload re-reads envVar, replacing the set of enabled subsystems.
+func (f *SubsysFilter) Reload() {
+	fields := strings.FieldsFunc(os.Getenv(f.envVar), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	all := false
+	enabled := make(map[string]bool, len(fields))
+	for _, name := range fields {
+		if name == "all" {
+			all = true
+		}
+		enabled[name] = true
+	}
+
+	f.mu.Lock()
+	f.all = all
+	f.enabled = enabled
+	f.mu.Unlock()
+}
+
+func (f *SubsysFilter) watchSIGHUP() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		for range c {
+			f.Reload()
+		}
+	}()
+}
+
+func subsysOf(r *Record) (string, bool) {
+	for i := 0; i < len(r.Ctx); i += 2 {
+		if r.Ctx[i] == subsysKey {
+			name, ok := r.Ctx[i+1].(string)
+			return name, ok
+		}
+	}
+	return "", false
+}
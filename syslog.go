@@ -0,0 +1,128 @@
+//go:build !windows
+// +build !windows
+
+package log15
+
+import (
+	"log/syslog"
+	"strings"
+	"sync"
+)
+
+// syslogWriter ships formatted records to a syslog daemon from a
+// backgroundQueue, dialing lazily and redialing with backoff if the
+// connection is lost. It mirrors netWriter but calls the severity-
+// specific method (Crit/Err/Warning/Info/Debug) so the daemon sees the
+// right priority for each record.
+type syslogWriter struct {
+	dial func() (*syslog.Writer, error)
+	q    *backgroundQueue
+
+	mu sync.Mutex
+	wr *syslog.Writer
+}
+
+func newSyslogWriter(dial func() (*syslog.Writer, error)) *syslogWriter {
+	return &syslogWriter{
+		dial: dial,
+		q:    newBackgroundQueue(netQueueSize, PolicyDrop),
+	}
+}
+
+func (w *syslogWriter) connect() (*syslog.Writer, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.wr != nil {
+		return w.wr, nil
+	}
+	wr, err := w.dial()
+	if err != nil {
+		return nil, err
+	}
+	w.wr = wr
+	return wr, nil
+}
+
+func (w *syslogWriter) reset() {
+	w.mu.Lock()
+	w.wr = nil
+	w.mu.Unlock()
+}
+
+func (w *syslogWriter) writeLevel(lvl Lvl, msg string) {
+	w.q.submit(func() error {
+		wr, err := w.connect()
+		if err != nil {
+			return err
+		}
+
+		var err2 error
+		switch lvl {
+		case LvlCrit:
+			err2 = wr.Crit(msg)
+		case LvlError:
+			err2 = wr.Err(msg)
+		case LvlWarn:
+			err2 = wr.Warning(msg)
+		case LvlInfo:
+			err2 = wr.Info(msg)
+		case LvlDebug:
+			err2 = wr.Debug(msg)
+		}
+		if err2 != nil {
+			w.reset()
+		}
+		return err2
+	})
+}
+
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	wr := w.wr
+	w.wr = nil
+	w.mu.Unlock()
+
+	if wr != nil {
+		wr.Close()
+	}
+	return w.q.Close()
+}
+
+// SyslogLocal opens a connection to the local syslog daemon and writes
+// all log records to it, mapping log15 levels onto syslog severities
+// (LvlCrit->LOG_CRIT, LvlError->LOG_ERR, LvlWarn->LOG_WARNING,
+// LvlInfo->LOG_INFO, LvlDebug->LOG_DEBUG).
+func SyslogLocal(tag string, fmtr Format) (Handler, error) {
+	return sharedSyslog(fmtr, func() (*syslog.Writer, error) {
+		return syslog.New(syslog.LOG_INFO|syslog.LOG_USER, tag)
+	})
+}
+
+// SyslogHandler opens a connection to a syslog daemon over the network
+// and writes all log records to it, reconnecting with backoff if the
+// connection drops. Severities are mapped the same way as SyslogLocal.
+func SyslogHandler(network, addr, tag string, fmtr Format) (Handler, error) {
+	return sharedSyslog(fmtr, func() (*syslog.Writer, error) {
+		return syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	})
+}
+
+func sharedSyslog(fmtr Format, dial func() (*syslog.Writer, error)) (Handler, error) {
+	// dial once up front so a bad address/daemon fails the caller
+	// immediately, the same as the old, synchronous SyslogHandler did.
+	wr, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	w := newSyslogWriter(func() (*syslog.Writer, error) { return dial() })
+	w.wr = wr
+
+	h := FuncHandler(func(r *Record) error {
+		msg := strings.TrimSuffix(string(fmtr.Format(r)), "\n")
+		w.writeLevel(r.Lvl, msg)
+		return nil
+	})
+	return &closingHandler{w, LazyHandler(h)}, nil
+}
@@ -0,0 +1,66 @@
+//go:build !windows
+// +build !windows
+
+package log15
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+// recordingFormat captures a copy of every record it's asked to format,
+// so tests can inspect what a Handler chain actually resolved Ctx to.
+type recordingFormat struct {
+	ch chan *Record
+}
+
+func (f recordingFormat) Format(r *Record) []byte {
+	cp := *r
+	cp.Ctx = append([]interface{}(nil), r.Ctx...)
+	f.ch <- &cp
+	return []byte("recorded\n")
+}
+
+func TestSyslogHandlerResolvesLazy(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() { io.Copy(ioutil.Discard, c) }()
+		}
+	}()
+
+	ch := make(chan *Record, 1)
+	h, err := SyslogHandler("tcp", ln.Addr().String(), "log15test", recordingFormat{ch})
+	if err != nil {
+		t.Fatalf("SyslogHandler returned error: %v", err)
+	}
+
+	l := New()
+	l.SetHandler(h)
+
+	x := 42
+	l.Info("test", "x", Lazy{func() int { return x }})
+
+	select {
+	case r := <-ch:
+		if r.Ctx[1] != 42 {
+			t.Fatalf("expected Lazy value resolved to 42 before formatting, got %v", r.Ctx[1])
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for record to reach the formatter")
+	}
+}